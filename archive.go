@@ -0,0 +1,120 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveDir bundles every file under dir into outPath, picking tar.gz or
+// zip by its extension. Archive entries are named relative to dir (the
+// module root) and keep the source file's mode bits.
+func archiveDir(dir, outPath string) error {
+	switch {
+	case strings.HasSuffix(outPath, ".tar.gz"), strings.HasSuffix(outPath, ".tgz"):
+		return archiveTarGz(dir, outPath)
+	case strings.HasSuffix(outPath, ".zip"):
+		return archiveZip(dir, outPath)
+	default:
+		return fmt.Errorf("unsupported archive extension for %q (want .tar.gz, .tgz or .zip)", outPath)
+	}
+}
+
+func archiveTarGz(dir, outPath string) (err error) {
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	gw := gzip.NewWriter(f)
+	defer func() {
+		if cerr := gw.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	tw := tar.NewWriter(gw)
+	defer func() {
+		if cerr := tw.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return walkFiles(dir, func(path, rel string, info os.FileInfo) error {
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		return copyFileInto(tw, path)
+	})
+}
+
+func archiveZip(dir, outPath string) (err error) {
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	zw := zip.NewWriter(f)
+	defer func() {
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return walkFiles(dir, func(path, rel string, info os.FileInfo) error {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		hdr.Method = zip.Deflate
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		return copyFileInto(w, path)
+	})
+}
+
+// walkFiles visits every regular file under dir and calls fn with its
+// path, its slash-separated path relative to dir, and its FileInfo.
+func walkFiles(dir string, fn func(path, rel string, info os.FileInfo) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, filepath.ToSlash(rel), info)
+	})
+}
+
+func copyFileInto(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveDirTarGz(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mymod.h"), []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mymod.c"), []byte("more"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "out.tar.gz")
+	if err := archiveDir(dir, out); err != nil {
+		t.Fatalf("archiveDir: %v", err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	tr := tar.NewReader(gr)
+
+	got := map[string]os.FileMode{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar.Next: %v", err)
+		}
+		got[hdr.Name] = os.FileMode(hdr.Mode) & 0o777
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %v", len(got), got)
+	}
+	if mode, ok := got["mymod.c"]; !ok {
+		t.Error("mymod.c: not archived")
+	} else if mode&0o100 == 0 {
+		t.Errorf("mymod.c: expected executable bit preserved, got mode %o", mode)
+	}
+	if _, ok := got["mymod.h"]; !ok {
+		t.Error("mymod.h: not archived")
+	}
+}
+
+func TestArchiveDirZip(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mymod.h"), []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "out.zip")
+	if err := archiveDir(dir, out); err != nil {
+		t.Fatalf("archiveDir: %v", err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("zip.OpenReader: %v", err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(zr.File))
+	}
+	if zr.File[0].Name != "mymod.h" {
+		t.Errorf("expected mymod.h, got %s", zr.File[0].Name)
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "content" {
+		t.Errorf("got %q, want %q", content, "content")
+	}
+}
+
+func TestArchiveDirBadExtension(t *testing.T) {
+	dir := t.TempDir()
+	if err := archiveDir(dir, filepath.Join(t.TempDir(), "out.rar")); err == nil {
+		t.Fatal("archiveDir: expected error for unsupported extension, got nil")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import "github.com/andrewz1/src-tree/pkg/srctree"
+
+const manifestFlag = "manifest"
+
+// runGen is the original single-purpose behaviour: write the tree to disk,
+// or regenerate it from a manifest previously produced by "plan".
+func runGen(args []string) {
+	fs, c := newCommonFlagSet(cmdGen)
+	manifest := fs.String(manifestFlag, "", "regenerate the tree from a manifest previously written by 'plan'")
+	fs.Parse(args)
+	if err := c.resolve(fs); err != nil {
+		logFlagErr(fs, err)
+	}
+	cfg, err := c.config()
+	if err != nil {
+		logErr(err)
+	}
+	if len(*manifest) > 0 {
+		m, err := readManifestFile(*manifest)
+		if err != nil {
+			logErr(err)
+		}
+		if err := srctree.Regenerate(m, cfg, srctree.OSFS{}); err != nil {
+			logErr(err)
+		}
+		return
+	}
+	if err := srctree.Run(cfg, srctree.OSFS{}); err != nil {
+		logErr(err)
+	}
+}
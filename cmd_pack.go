@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/andrewz1/src-tree/pkg/srctree"
+)
+
+// runPack generates the tree into a temp dir and streams the result into
+// a single archive, so callers get a ready-to-drop-in header tree without
+// having to manage a working directory themselves.
+func runPack(args []string) {
+	fs, c := newCommonFlagSet(cmdPack)
+	out := fs.String("o", "", "output archive path (.tar.gz, .tgz or .zip)")
+	fs.Parse(args)
+	if err := c.resolve(fs); err != nil {
+		logFlagErr(fs, err)
+	}
+	if len(*out) == 0 {
+		logFlagErr(fs, fmt.Errorf("flag -o is required"))
+	}
+	cfg, err := c.config()
+	if err != nil {
+		logErr(err)
+	}
+
+	dir, err := os.MkdirTemp("", "src-tree-pack-*")
+	if err != nil {
+		logErr(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		logErr(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		logErr(err)
+	}
+	genErr := srctree.Run(cfg, srctree.OSFS{})
+	if err := os.Chdir(wd); err != nil {
+		logErr(err)
+	}
+	if genErr != nil {
+		logErr(genErr)
+	}
+
+	if err := archiveDir(dir, *out); err != nil {
+		logErr(err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+
+	"github.com/andrewz1/src-tree/pkg/srctree"
+)
+
+// runPlan emits the planned tree as a JSON manifest on stdout instead of
+// writing any files.
+func runPlan(args []string) {
+	fs, c := newCommonFlagSet(cmdPlan)
+	fs.Parse(args)
+	if err := c.resolve(fs); err != nil {
+		logFlagErr(fs, err)
+	}
+	cfg, err := c.config()
+	if err != nil {
+		logErr(err)
+	}
+	m, err := srctree.Plan(cfg)
+	if err != nil {
+		logErr(err)
+	}
+	if err := srctree.WriteManifest(os.Stdout, m); err != nil {
+		logErr(err)
+	}
+}
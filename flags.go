@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andrewz1/src-tree/internal/template"
+	"github.com/andrewz1/src-tree/pkg/srctree"
+)
+
+const (
+	nameFlag     = "name"
+	dirFlag      = "dir"
+	onceFlag     = "once"
+	pubFlag      = "pub"
+	addFlag      = "add"
+	tmplFlag     = "tmpl"
+	buildsysFlag = "buildsys"
+
+	pPub = "pub"
+)
+
+// commonFlags are the generation options shared by gen, plan and pack.
+type commonFlags struct {
+	name     string // module name
+	useDir   bool   // current dir is module name
+	once     bool   // add #pragma once
+	pub      bool   // generate only public tree
+	pfx      string // prefix for files
+	tmpl     string // path to stub config for -tmpl
+	buildsys string // cmake/meson/make build fragment to emit, or ""
+
+	isNameSet bool
+	isDirSet  bool
+	isPfxSet  bool
+	isTmplSet bool
+}
+
+// newCommonFlagSet registers the shared flags on a fresh FlagSet for the
+// named subcommand.
+func newCommonFlagSet(cmd string) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	c := &commonFlags{}
+	fs.StringVar(&c.name, nameFlag, "", "module (parent dir) name")
+	fs.BoolVar(&c.useDir, dirFlag, false, "ude directory name as name")
+	fs.BoolVar(&c.once, onceFlag, false, "add #pragma once to includes")
+	fs.BoolVar(&c.pub, pubFlag, false, "generate only public part of tree")
+	fs.StringVar(&c.pfx, addFlag, "", "add part of tree with given prefix (for example xxx give the xxx_consts.h and so on)")
+	fs.StringVar(&c.tmpl, tmplFlag, "", "path to a stub config used to fill in generated bodies")
+	fs.StringVar(&c.buildsys, buildsysFlag, "", "also emit a build fragment: cmake, meson or make")
+	return fs, c
+}
+
+// resolve validates and fills in defaults for the shared flags once fs has
+// parsed argv.
+func (c *commonFlags) resolve(fs *flag.FlagSet) error {
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case nameFlag:
+			c.isNameSet = true
+		case dirFlag:
+			c.isDirSet = true
+		case addFlag:
+			c.isPfxSet = true
+		case tmplFlag:
+			c.isTmplSet = true
+		}
+	})
+	if c.isNameSet && c.isDirSet {
+		return fmt.Errorf("flags -%s and -%s not compatible", nameFlag, dirFlag)
+	}
+	if c.isPfxSet {
+		if len(c.pfx) == 0 {
+			return fmt.Errorf("flag -%s must have non empty arg", addFlag)
+		}
+	} else {
+		c.pfx = pPub
+	}
+	switch c.buildsys {
+	case "", srctree.BuildSysCMake, srctree.BuildSysMeson, srctree.BuildSysMake:
+	default:
+		return fmt.Errorf("flag -%s must be one of %s, %s, %s", buildsysFlag, srctree.BuildSysCMake, srctree.BuildSysMeson, srctree.BuildSysMake)
+	}
+	if !c.isDirSet {
+		return nil
+	}
+	path, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	c.name = filepath.Base(path)
+	return nil
+}
+
+// config builds the srctree.Config for these flags, loading the -tmpl
+// stub config if one was given.
+func (c *commonFlags) config() (srctree.Config, error) {
+	var stubs *template.Stubs
+	if c.isTmplSet {
+		s, err := template.Load(c.tmpl)
+		if err != nil {
+			return srctree.Config{}, err
+		}
+		stubs = s
+	}
+	return srctree.Config{
+		Name:     c.name,
+		Once:     c.once,
+		Pub:      c.pub,
+		Prefix:   c.pfx,
+		IsCustom: c.isPfxSet,
+		Stubs:    stubs,
+		BuildSys: c.buildsys,
+	}, nil
+}
+
+func readManifestFile(path string) (*srctree.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return srctree.ReadManifest(f)
+}
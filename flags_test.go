@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestCommonFlagsResolve(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		wantErr bool
+		check   func(t *testing.T, c *commonFlags)
+	}{
+		{
+			name: "defaults to pub prefix",
+			args: nil,
+			check: func(t *testing.T, c *commonFlags) {
+				if c.pfx != pPub {
+					t.Errorf("pfx = %q, want %q", c.pfx, pPub)
+				}
+			},
+		},
+		{
+			name:    "name and dir are incompatible",
+			args:    []string{"-name", "mymod", "-dir"},
+			wantErr: true,
+		},
+		{
+			name:    "empty -add value rejected",
+			args:    []string{"-add", ""},
+			wantErr: true,
+		},
+		{
+			name:    "invalid buildsys value rejected",
+			args:    []string{"-buildsys", "bogus"},
+			wantErr: true,
+		},
+		{
+			name: "valid buildsys value accepted",
+			args: []string{"-buildsys", "cmake"},
+			check: func(t *testing.T, c *commonFlags) {
+				if c.buildsys != "cmake" {
+					t.Errorf("buildsys = %q, want %q", c.buildsys, "cmake")
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, c := newCommonFlagSet(cmdGen)
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			err := c.resolve(fs)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("resolve: expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if tc.check != nil {
+				tc.check(t, c)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Package template renders the body stubs used to fill in the
+// otherwise-empty headers and sources that src-tree generates.
+package template
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Vars are the substitution variables available inside a stub template.
+type Vars struct {
+	Module string // module (parent dir) name
+	Prefix string // file prefix (pub/priv/custom)
+	Guard  string // include guard macro for the file being rendered
+	Date   string // generation date, YYYY-MM-DD
+}
+
+// Stubs holds the per-suffix body templates read from a config file.
+type Stubs struct {
+	Consts  string `yaml:"consts"`  // body for *_consts.h
+	Types   string `yaml:"types"`   // body for *_types.h
+	Inlines string `yaml:"inlines"` // body for *_inlines.h
+	Source  string `yaml:"source"`  // body for the generated .c file
+}
+
+// Load reads a YAML stub config file mapping section names to
+// text/template bodies, e.g.:
+//
+//	consts: |
+//	  #define {{.Prefix | upper}}_VERSION 1
+//	types: |
+//	  typedef struct {{.Prefix}}_s {{.Prefix}}_t;
+//
+// Keys other than consts, types, inlines and source are ignored so the
+// format can grow without breaking older configs.
+func Load(path string) (*Stubs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &Stubs{}
+	if err := yaml.NewDecoder(f).Decode(s); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse stub config %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// funcs are the extra template functions available to stub bodies, on top
+// of the usual text/template builtins.
+var funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Render executes a stub body against vars. An empty stub renders to an
+// empty string so callers can skip writing it out entirely.
+func Render(stub string, vars Vars) (string, error) {
+	if stub == "" {
+		return "", nil
+	}
+	t, err := template.New("stub").Funcs(funcs).Parse(stub)
+	if err != nil {
+		return "", fmt.Errorf("parse stub: %w", err)
+	}
+	var buf strings.Builder
+	if err = t.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("render stub: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,92 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderUpperFunc(t *testing.T) {
+	out, err := Render("#define {{.Prefix | upper}}_VERSION 1", Vars{Prefix: "pub"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if want := "#define PUB_VERSION 1"; out != want {
+		t.Errorf("Render: got %q, want %q", out, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    Stubs
+		wantErr bool
+	}{
+		{
+			name: "all sections",
+			content: "consts: |\n" +
+				"  #define {{.Prefix}}_VERSION 1\n" +
+				"types: |\n" +
+				"  typedef struct {{.Prefix}}_s {{.Prefix}}_t;\n" +
+				"inlines: |\n" +
+				"  static inline void {{.Prefix}}_noop(void) {}\n" +
+				"source: |\n" +
+				"  int {{.Module}}_init(void) { return 0; }\n",
+			want: Stubs{
+				Consts:  "#define {{.Prefix}}_VERSION 1\n",
+				Types:   "typedef struct {{.Prefix}}_s {{.Prefix}}_t;\n",
+				Inlines: "static inline void {{.Prefix}}_noop(void) {}\n",
+				Source:  "int {{.Module}}_init(void) { return 0; }\n",
+			},
+		},
+		{
+			name:    "missing sections leave zero values",
+			content: "consts: \"#define X 1\"\n",
+			want:    Stubs{Consts: "#define X 1"},
+		},
+		{
+			name:    "unknown keys are ignored",
+			content: "consts: \"#define X 1\"\nbogus: \"ignored\"\n",
+			want:    Stubs{Consts: "#define X 1"},
+		},
+		{
+			name:    "empty file",
+			content: "",
+			want:    Stubs{},
+		},
+		{
+			name:    "malformed yaml",
+			content: "consts: [this is not a string\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "stubs.yaml")
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Load(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Load: expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if *got != tc.want {
+				t.Errorf("Load: got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "does-not-exist.yaml")); err == nil {
+		t.Fatal("Load: expected error for missing file, got nil")
+	}
+}
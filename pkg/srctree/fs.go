@@ -0,0 +1,39 @@
+package srctree
+
+import (
+	"io"
+	"os"
+)
+
+const fileMode = 0644
+
+// FS abstracts the handful of filesystem operations the generator needs,
+// so callers can run it against an in-memory tree (tests, embedding in
+// other tools) as well as the real filesystem.
+type FS interface {
+	// Create opens name for writing, failing if it already exists (the
+	// same exclusive-create semantics os.OpenFile gives with O_EXCL).
+	Create(name string) (io.WriteCloser, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Stat(name string) (os.FileInfo, error)
+	Remove(name string) error
+}
+
+// OSFS implements FS on top of the real filesystem.
+type OSFS struct{}
+
+func (OSFS) Create(name string) (io.WriteCloser, error) {
+	return os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_TRUNC, fileMode)
+}
+
+func (OSFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (OSFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
@@ -0,0 +1,128 @@
+package srctree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// ManifestEntry describes a single file Run would create: its path
+// relative to the working directory, its include guard macro (headers
+// only) and the list of files it #includes.
+type ManifestEntry struct {
+	Path     string   `json:"path"`
+	Guard    string   `json:"guard,omitempty"`
+	Includes []string `json:"includes,omitempty"`
+	IsSource bool     `json:"is_source,omitempty"`
+	// Body holds verbatim content for entries that aren't derived from
+	// the usual header/include/stub machinery, e.g. a -buildsys fragment.
+	Body string `json:"body,omitempty"`
+}
+
+// Manifest is the full set of files a Config would produce.
+type Manifest struct {
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// Plan walks the same tree layout Run would build and records what it
+// would do as a Manifest, without touching any filesystem.
+func Plan(cfg Config) (*Manifest, error) {
+	m := &Manifest{}
+	g := newGenerator(cfg)
+	g.record = func(e ManifestEntry) { m.Entries = append(m.Entries, e) }
+	if err := g.runAll(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WriteManifest encodes m as indented JSON.
+func WriteManifest(w io.Writer, m *Manifest) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// ReadManifest decodes a Manifest previously written by WriteManifest.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Regenerate deterministically recreates every file listed in m against
+// fs, using cfg only for options that affect rendering (Once, Stubs) and
+// not for the tree layout itself, which comes entirely from m.
+func Regenerate(m *Manifest, cfg Config, fs FS) error {
+	g := newGenerator(cfg)
+	g.fs = fs
+	for _, e := range m.Entries {
+		if err := g.writeManifestEntry(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) writeManifestEntry(e ManifestEntry) (err error) {
+	var (
+		w  io.WriteCloser
+		fn string
+	)
+	// Entries carrying a verbatim Body (e.g. a -buildsys fragment) must
+	// keep their exact-case path, like createRaw does for Run.
+	resolve := absLower
+	if len(e.Body) > 0 {
+		resolve = filepath.Abs
+	}
+	if w, fn, err = g.createAt(e.Path, resolve); err != nil {
+		return
+	}
+	defer func() {
+		w.Close()
+		if err != nil {
+			g.fs.Remove(fn)
+		}
+	}()
+	if len(e.Body) > 0 {
+		_, err = io.WriteString(w, e.Body)
+		return
+	}
+	if !e.IsSource {
+		if err = g.writeHeaderGuard(w, e.Guard); err != nil {
+			return
+		}
+	}
+	for _, inc := range e.Includes {
+		if _, err = fmt.Fprintf(w, "#include \"%s\"\n", inc); err != nil {
+			return
+		}
+	}
+	if len(e.Includes) > 0 {
+		if _, err = w.Write([]byte{'\n'}); err != nil {
+			return
+		}
+	}
+	if err = g.writeBody(w, e.Path); err != nil {
+		return
+	}
+	if !e.IsSource {
+		_, err = fmt.Fprintf(w, "#endif //%s\n", e.Guard)
+	}
+	return
+}
+
+// writeHeaderGuard is writeHeader driven by an already-known guard macro
+// (from a manifest entry) rather than one recomputed from a filename.
+func (g *generator) writeHeaderGuard(w io.Writer, guard string) (err error) {
+	if g.cfg.Once {
+		if _, err = fmt.Fprintf(w, "#pragma once\n\n"); err != nil {
+			return
+		}
+	}
+	_, err = fmt.Fprintf(w, "#ifndef %s\n#define %s\n\n", guard, guard)
+	return
+}
@@ -0,0 +1,402 @@
+// Package srctree generates the boilerplate include/source tree used to
+// scaffold a new C module: *_consts.h, *_types.h, *_inlines.h, the public
+// umbrella header/source and, for full trees, the private counterparts.
+package srctree
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andrewz1/src-tree/internal/template"
+)
+
+const (
+	byteUp = 'a' - 'A'
+
+	sConsts  = "_consts.h"
+	sTypes   = "_types.h"
+	sInlines = "_inlines.h"
+	pPub     = "pub"
+	pPriv    = "priv"
+
+	// BuildSysCMake, BuildSysMeson and BuildSysMake are the accepted
+	// values for Config.BuildSys.
+	BuildSysCMake = "cmake"
+	BuildSysMeson = "meson"
+	BuildSysMake  = "make"
+)
+
+// Config describes one generation run. Flag resolution (e.g. -dir reading
+// the working directory into Name) is the caller's job; Config only holds
+// the final, resolved values.
+type Config struct {
+	Name     string          // module (parent dir) name, may be empty
+	Once     bool            // add #pragma once to includes
+	Pub      bool            // generate only public part of the tree
+	Prefix   string          // prefix for a single custom-prefix tree
+	IsCustom bool            // Prefix was explicitly set (single add mode)
+	Stubs    *template.Stubs // body stubs to fill generated files, may be nil
+	BuildSys string          // BuildSysCMake, BuildSysMeson, BuildSysMake, or "" for none
+}
+
+// Run generates the tree described by cfg against fs.
+func Run(cfg Config, fs FS) error {
+	g := newGenerator(cfg)
+	g.fs = fs
+	return g.runAll()
+}
+
+func newGenerator(cfg Config) *generator {
+	g := &generator{cfg: cfg, pfx: pPub}
+	if cfg.IsCustom {
+		g.pfx = cfg.Prefix
+	}
+	return g
+}
+
+// generator holds the mutable state of a single Run/Plan: the resolved
+// config, the target FS, and the prefix of the tree currently being built
+// (pub, then priv for full trees). When record is set, file creation is
+// diverted into manifest entries instead of being written to fs (see
+// Plan in manifest.go).
+type generator struct {
+	cfg    Config
+	fs     FS
+	pfx    string
+	record func(ManifestEntry)
+}
+
+// runAll walks the same public/private tree layout main has always built,
+// driving either Run (fs writes) or Plan (manifest recording) depending on
+// which of g.fs/g.record is set, then emits the optional build-system
+// fragment.
+func (g *generator) runAll() error {
+	if err := g.buildTree(); err != nil {
+		return err
+	}
+	return g.writeBuildSys()
+}
+
+func (g *generator) buildTree() error {
+	if err := g.createIncs(true); err != nil {
+		return err
+	}
+	if g.cfg.IsCustom {
+		if err := g.createNamedInc(g.pfx+".h", g.fName(sInlines)); err != nil {
+			return err
+		}
+		return g.createNamedSrc(g.pfx+".c", g.pfx+".h")
+	}
+	if len(g.cfg.Name) > 0 {
+		if err := g.createNamedInc(g.cfg.Name+".h", g.fName(sInlines)); err != nil {
+			return err
+		}
+	}
+	if g.cfg.Pub {
+		return nil
+	}
+	g.pfx = pPriv
+	if err := g.createIncs(false); err != nil {
+		return err
+	}
+	if len(g.cfg.Name) > 0 {
+		return g.createNamedSrc(g.cfg.Name+".c", g.fName(sInlines))
+	}
+	return nil
+}
+
+func (g *generator) fName(suf string) string {
+	return g.pfx + suf
+}
+
+func (g *generator) createIncs(first bool) error {
+	if first {
+		if err := g.createNamedInc(g.fName(sConsts)); err != nil {
+			return err
+		}
+	} else {
+		// this is possible only in full creation
+		if err := g.createNamedInc(g.fName(sConsts), pPub+sInlines); err != nil {
+			return err
+		}
+	}
+	if err := g.createNamedInc(g.fName(sTypes), g.fName(sConsts)); err != nil {
+		return err
+	}
+	return g.createNamedInc(g.fName(sInlines), g.fName(sTypes))
+}
+
+func (g *generator) defName(iname string) string {
+	n := g.includeName(iname)
+	r := make([]byte, 0, len(n)+8)
+	r = append(r, '_', '_')
+	for _, b := range []byte(n) {
+		switch {
+		case b >= '0' && b <= '9':
+			r = append(r, b)
+		case b >= 'A' && b <= 'Z':
+			r = append(r, b)
+		case b >= 'a' && b <= 'z':
+			r = append(r, b-byteUp)
+		default:
+			r = append(r, '_')
+		}
+	}
+	r = append(r, '_', '_')
+	return string(r)
+}
+
+func (g *generator) includeName(iname string) string {
+	if len(g.cfg.Name) == 0 {
+		return iname
+	}
+	return g.cfg.Name + "/" + iname
+}
+
+// resolveIncludes maps a list of bare include names to the resolved
+// "#include" targets (module-qualified when a module name is set).
+func (g *generator) resolveIncludes(inc []string) []string {
+	if len(inc) == 0 {
+		return nil
+	}
+	resolved := make([]string, len(inc))
+	for i, n := range inc {
+		resolved[i] = g.includeName(n)
+	}
+	return resolved
+}
+
+func (g *generator) writeHeader(w io.Writer, iname string) (err error) {
+	if g.cfg.Once {
+		if _, err = fmt.Fprintf(w, "#pragma once\n\n"); err != nil {
+			return
+		}
+	}
+	def := g.defName(iname)
+	_, err = fmt.Fprintf(w, "#ifndef %s\n#define %s\n\n", def, def)
+	return
+}
+
+func (g *generator) writeFooter(w io.Writer, iname string) (err error) {
+	_, err = fmt.Fprintf(w, "#endif //%s\n", g.defName(iname))
+	return
+}
+
+func (g *generator) writeInclude(w io.Writer, iname string) (err error) {
+	_, err = fmt.Fprintf(w, "#include \"%s\"\n", g.includeName(iname))
+	return
+}
+
+// stubFor returns the raw stub body that applies to iname, or "" if no
+// stub config was loaded or none matches this file's suffix.
+func (g *generator) stubFor(iname string) string {
+	if g.cfg.Stubs == nil {
+		return ""
+	}
+	switch {
+	case strings.HasSuffix(iname, sConsts):
+		return g.cfg.Stubs.Consts
+	case strings.HasSuffix(iname, sTypes):
+		return g.cfg.Stubs.Types
+	case strings.HasSuffix(iname, sInlines):
+		return g.cfg.Stubs.Inlines
+	case strings.HasSuffix(iname, ".c"):
+		return g.cfg.Stubs.Source
+	default:
+		return ""
+	}
+}
+
+func (g *generator) stubVars(iname string) template.Vars {
+	return template.Vars{
+		Module: g.cfg.Name,
+		Prefix: g.pfx,
+		Guard:  g.defName(iname),
+		Date:   time.Now().Format("2006-01-02"),
+	}
+}
+
+func (g *generator) writeBody(w io.Writer, iname string) (err error) {
+	body, err := template.Render(g.stubFor(iname), g.stubVars(iname))
+	if err != nil || len(body) == 0 {
+		return
+	}
+	_, err = fmt.Fprintf(w, "%s\n", body)
+	return
+}
+
+// absLower resolves fname to an absolute, lower-cased path, matching the
+// case-insensitive-filesystem-friendly naming the generator has always used
+// for the .h/.c files it generates from the module name and prefix.
+func absLower(fname string) (string, error) {
+	return filepath.Abs(strings.ToLower(fname))
+}
+
+func (g *generator) createFile(fname string) (io.WriteCloser, string, error) {
+	return g.createAt(fname, absLower)
+}
+
+// createAt resolves fname via resolve and opens it through g.fs, returning
+// the open file and the path it was created at.
+func (g *generator) createAt(fname string, resolve func(string) (string, error)) (io.WriteCloser, string, error) {
+	fn, err := resolve(fname)
+	if err != nil {
+		return nil, "", err
+	}
+	fd, err := g.fs.Create(fn)
+	if err != nil {
+		return nil, "", err
+	}
+	return fd, fn, nil
+}
+
+func (g *generator) createNamedInc(iname string, inc ...string) (err error) {
+	if g.record != nil {
+		g.record(ManifestEntry{Path: iname, Guard: g.defName(iname), Includes: g.resolveIncludes(inc)})
+		return nil
+	}
+	var (
+		w  io.WriteCloser
+		fn string
+	)
+	if w, fn, err = g.createFile(iname); err != nil {
+		return
+	}
+	defer func() {
+		w.Close()
+		if err != nil {
+			g.fs.Remove(fn)
+		}
+	}()
+	if err = g.writeHeader(w, iname); err != nil {
+		return
+	}
+	for _, incName := range inc {
+		if err = g.writeInclude(w, incName); err != nil {
+			return
+		}
+	}
+	if len(inc) > 0 {
+		if _, err = w.Write([]byte{'\n'}); err != nil {
+			return
+		}
+	}
+	if err = g.writeBody(w, iname); err != nil {
+		return
+	}
+	if err = g.writeFooter(w, iname); err != nil {
+		return
+	}
+	return
+}
+
+func (g *generator) createNamedSrc(sname string, inc ...string) (err error) {
+	if g.record != nil {
+		g.record(ManifestEntry{Path: sname, Includes: g.resolveIncludes(inc), IsSource: true})
+		return nil
+	}
+	var (
+		w  io.WriteCloser
+		fn string
+	)
+	if w, fn, err = g.createFile(sname); err != nil {
+		return
+	}
+	defer func() {
+		w.Close()
+		if err != nil {
+			g.fs.Remove(fn)
+		}
+	}()
+	for _, incName := range inc {
+		if err = g.writeInclude(w, incName); err != nil {
+			return
+		}
+	}
+	if len(inc) > 0 {
+		if _, err = w.Write([]byte{'\n'}); err != nil {
+			return
+		}
+	}
+	err = g.writeBody(w, sname)
+	return
+}
+
+// libName is the name the build-system fragment builds a library under:
+// the custom prefix for a single add tree, otherwise the module name.
+func (g *generator) libName() string {
+	if g.cfg.IsCustom {
+		return g.cfg.Prefix
+	}
+	return g.cfg.Name
+}
+
+// buildSysFragment renders the -buildsys fragment file, if any. A -pub
+// tree (no module .c generated) gets an interface-only fragment.
+func (g *generator) buildSysFragment() (fname, content string) {
+	lib := g.libName()
+	headerOnly := g.cfg.Pub && !g.cfg.IsCustom
+	srcFile := lib + ".c"
+	switch g.cfg.BuildSys {
+	case BuildSysCMake:
+		fname = "CMakeLists.txt"
+		if headerOnly {
+			content = fmt.Sprintf("add_library(%s INTERFACE)\ntarget_include_directories(%s INTERFACE .)\n", lib, lib)
+		} else {
+			content = fmt.Sprintf("add_library(%s %s)\ntarget_include_directories(%s PUBLIC .)\n", lib, srcFile, lib)
+		}
+	case BuildSysMeson:
+		fname = "meson.build"
+		if headerOnly {
+			content = fmt.Sprintf("%s_inc = include_directories('.')\n", lib)
+		} else {
+			content = fmt.Sprintf("%s_lib = library('%s', '%s', include_directories: include_directories('.'))\n", lib, lib, srcFile)
+		}
+	case BuildSysMake:
+		fname = "Makefile.inc"
+		if headerOnly {
+			content = "HDRS += $(wildcard *.h)\n"
+		} else {
+			content = fmt.Sprintf("SRCS += %s\nHDRS += $(wildcard *.h)\n", srcFile)
+		}
+	}
+	return
+}
+
+func (g *generator) writeBuildSys() error {
+	if g.cfg.BuildSys == "" {
+		return nil
+	}
+	if g.libName() == "" {
+		return fmt.Errorf("-buildsys requires a library name: pass -name, -dir or -add")
+	}
+	fname, content := g.buildSysFragment()
+	if g.record != nil {
+		g.record(ManifestEntry{Path: fname, Body: content, IsSource: true})
+		return nil
+	}
+	return g.createRaw(fname, content)
+}
+
+// createRaw writes a file whose name must keep its exact case (e.g.
+// CMakeLists.txt), unlike the generated .h/.c files which are lower-cased.
+func (g *generator) createRaw(fname, content string) (err error) {
+	var (
+		w  io.WriteCloser
+		fn string
+	)
+	if w, fn, err = g.createAt(fname, filepath.Abs); err != nil {
+		return
+	}
+	defer func() {
+		w.Close()
+		if err != nil {
+			g.fs.Remove(fn)
+		}
+	}()
+	_, err = io.WriteString(w, content)
+	return
+}
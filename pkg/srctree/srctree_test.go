@@ -0,0 +1,290 @@
+package srctree
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andrewz1/src-tree/internal/template"
+)
+
+// memFS is a minimal in-memory FS used to exercise the generator without
+// touching the real filesystem.
+type memFS struct {
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: make(map[string]string)}
+}
+
+func (m *memFS) Create(name string) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; ok {
+		return nil, os.ErrExist
+	}
+	m.files[name] = ""
+	return &memFile{fs: m, name: name}, nil
+}
+
+func (m *memFS) MkdirAll(string, os.FileMode) error { return nil }
+
+func (m *memFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; !ok {
+		return nil, os.ErrNotExist
+	}
+	return nil, nil
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, name)
+	return nil
+}
+
+func (m *memFS) get(name string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.files[name]
+	return c, ok
+}
+
+type memFile struct {
+	fs   *memFS
+	name string
+	buf  bytes.Buffer
+}
+
+func (f *memFile) Write(p []byte) (int, error) { return f.buf.Write(p) }
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	f.fs.files[f.name] = f.buf.String()
+	f.fs.mu.Unlock()
+	return nil
+}
+
+func TestRunPublicTree(t *testing.T) {
+	fs := newMemFS()
+	cfg := Config{Name: "mymod", Pub: true}
+	if err := Run(cfg, fs); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, want := range []struct {
+		name     string
+		contains []string
+	}{
+		{"pub_consts.h", []string{"#ifndef __MYMOD_PUB_CONSTS_H__"}},
+		{"pub_types.h", []string{"#include \"mymod/pub_consts.h\""}},
+		{"pub_inlines.h", []string{"#include \"mymod/pub_types.h\""}},
+		{"mymod.h", []string{"#include \"mymod/pub_inlines.h\""}},
+	} {
+		path, err := absLower(want.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, ok := fs.get(path)
+		if !ok {
+			t.Fatalf("%s: not created", want.name)
+		}
+		for _, s := range want.contains {
+			if !strings.Contains(content, s) {
+				t.Errorf("%s: expected to contain %q, got:\n%s", want.name, s, content)
+			}
+		}
+	}
+	if _, ok := fs.get(mustAbsLower(t, "mymod.c")); ok {
+		t.Errorf("mymod.c should not be created for -pub")
+	}
+}
+
+func TestRunFullTreeWithStubs(t *testing.T) {
+	fs := newMemFS()
+	cfg := Config{
+		Name: "mymod",
+		Stubs: &template.Stubs{
+			Consts: "#define {{.Prefix}}_VERSION 1",
+			Source: "int {{.Module}}_init(void) { return 0; }",
+		},
+	}
+	if err := Run(cfg, fs); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	content, ok := fs.get(mustAbsLower(t, "pub_consts.h"))
+	if !ok {
+		t.Fatal("pub_consts.h: not created")
+	}
+	if !strings.Contains(content, "#define pub_VERSION 1") {
+		t.Errorf("pub_consts.h: missing rendered stub, got:\n%s", content)
+	}
+
+	content, ok = fs.get(mustAbsLower(t, "mymod.c"))
+	if !ok {
+		t.Fatal("mymod.c: not created")
+	}
+	if !strings.Contains(content, "int mymod_init(void) { return 0; }") {
+		t.Errorf("mymod.c: missing rendered stub, got:\n%s", content)
+	}
+}
+
+func TestRunBuildSysFragment(t *testing.T) {
+	fs := newMemFS()
+	cfg := Config{Name: "mymod", Pub: true, BuildSys: BuildSysCMake}
+	if err := Run(cfg, fs); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	path, err := filepath.Abs("CMakeLists.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, ok := fs.get(path)
+	if !ok {
+		t.Fatalf("CMakeLists.txt: not created at exact-case path %s", path)
+	}
+	if !strings.Contains(content, "add_library(mymod INTERFACE)") {
+		t.Errorf("CMakeLists.txt: expected interface library for -pub tree, got:\n%s", content)
+	}
+	if _, ok := fs.get(mustAbsLower(t, "CMakeLists.txt")); ok {
+		t.Error("CMakeLists.txt: should not also be written lower-cased")
+	}
+}
+
+func TestBuildSysRequiresLibName(t *testing.T) {
+	fs := newMemFS()
+	cfg := Config{Pub: true, BuildSys: BuildSysCMake}
+	if err := Run(cfg, fs); err == nil {
+		t.Fatal("Run: expected an error for -buildsys with no usable library name, got nil")
+	}
+}
+
+func TestRegenerateBuildSysKeepsExactCase(t *testing.T) {
+	cfg := Config{Name: "mymod", Pub: true, BuildSys: BuildSysCMake}
+
+	m, err := Plan(cfg)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	regenFS := newMemFS()
+	if err := Regenerate(m, cfg, regenFS); err != nil {
+		t.Fatalf("Regenerate: %v", err)
+	}
+	runFS := newMemFS()
+	if err := Run(cfg, runFS); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	path, err := filepath.Abs("CMakeLists.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runContent, ok := runFS.get(path)
+	if !ok {
+		t.Fatalf("Run: CMakeLists.txt not created at exact-case path %s", path)
+	}
+	regenContent, ok := regenFS.get(path)
+	if !ok {
+		t.Fatalf("Regenerate: CMakeLists.txt not created at exact-case path %s (manifest path disagrees with Run)", path)
+	}
+	if regenContent != runContent {
+		t.Errorf("Regenerate content differs from Run\nrun:   %q\nregen: %q", runContent, regenContent)
+	}
+}
+
+func TestRunExclusiveCreate(t *testing.T) {
+	fs := newMemFS()
+	cfg := Config{Name: "mymod", Pub: true}
+	if err := Run(cfg, fs); err != nil {
+		t.Fatalf("first Run: %v", err)
+	}
+	if err := Run(cfg, fs); err == nil {
+		t.Fatal("second Run: expected error from exclusive create, got nil")
+	}
+}
+
+func TestPlanMatchesRun(t *testing.T) {
+	cfg := Config{Name: "mymod"}
+
+	m, err := Plan(cfg)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(m.Entries) == 0 {
+		t.Fatal("Plan: no entries")
+	}
+
+	ranFS := newMemFS()
+	if err := Run(cfg, ranFS); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(m.Entries) != len(ranFS.files) {
+		t.Fatalf("Plan produced %d entries, Run produced %d files", len(m.Entries), len(ranFS.files))
+	}
+
+	for _, e := range m.Entries {
+		if _, err := absLower(e.Path); err != nil {
+			t.Fatalf("%s: %v", e.Path, err)
+		}
+	}
+}
+
+func TestRegenerateFromManifest(t *testing.T) {
+	cfg := Config{Name: "mymod"}
+
+	m, err := Plan(cfg)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, m); err != nil {
+		t.Fatalf("WriteManifest: %v", err)
+	}
+	decoded, err := ReadManifest(&buf)
+	if err != nil {
+		t.Fatalf("ReadManifest: %v", err)
+	}
+
+	regenFS := newMemFS()
+	if err := Regenerate(decoded, cfg, regenFS); err != nil {
+		t.Fatalf("Regenerate: %v", err)
+	}
+
+	runFS := newMemFS()
+	if err := Run(cfg, runFS); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(regenFS.files) != len(runFS.files) {
+		t.Fatalf("Regenerate produced %d files, Run produced %d", len(regenFS.files), len(runFS.files))
+	}
+	for path, content := range runFS.files {
+		got, ok := regenFS.files[path]
+		if !ok {
+			t.Fatalf("%s: not regenerated", path)
+		}
+		if got != content {
+			t.Errorf("%s: regenerated content differs\nrun:   %q\nregen: %q", path, content, got)
+		}
+	}
+}
+
+func mustAbsLower(t *testing.T, name string) string {
+	t.Helper()
+	p, err := absLower(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}